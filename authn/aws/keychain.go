@@ -0,0 +1,85 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aws implements the authn.Keychain interface for Amazon ECR
+// registries, using the default AWS credential chain to mint a short-lived
+// authorization token.
+package aws
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+
+	"github.com/google/go-containerregistry/authn"
+	"github.com/google/go-containerregistry/name"
+)
+
+// Keychain implements authn.Keychain for Amazon ECR registries, e.g.
+// 123456789012.dkr.ecr.us-east-1.amazonaws.com.
+var Keychain authn.Keychain = &ecrKeychain{}
+
+var ecrRegistry = regexp.MustCompile(`^\d+\.dkr\.ecr\.([a-z0-9-]+)\.amazonaws\.com$`)
+
+// refreshBefore controls how far ahead of ECR's reported expiry we refresh,
+// so a slow caller never hands out a token about to be rejected.
+const refreshBefore = 5 * time.Minute
+
+type ecrKeychain struct {
+	mu      sync.Mutex
+	tokens  map[string]*authn.Basic
+	expires map[string]time.Time
+}
+
+func (k *ecrKeychain) Resolve(reg name.Registry) (authn.Authenticator, error) {
+	m := ecrRegistry.FindStringSubmatch(reg.Name())
+	if m == nil {
+		return authn.Anonymous, nil
+	}
+	region := m[1]
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.tokens == nil {
+		k.tokens = map[string]*authn.Basic{}
+		k.expires = map[string]time.Time{}
+	}
+	if auth, ok := k.tokens[region]; ok && time.Now().Before(k.expires[region]) {
+		return auth, nil
+	}
+
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return authn.Anonymous, nil
+	}
+	out, err := ecr.New(sess).GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{})
+	if err != nil || len(out.AuthorizationData) == 0 {
+		return authn.Anonymous, nil
+	}
+
+	data := out.AuthorizationData[0]
+	user, pass, err := decodeBasicToken(aws.StringValue(data.AuthorizationToken))
+	if err != nil {
+		return authn.Anonymous, nil
+	}
+
+	auth := &authn.Basic{Username: user, Password: pass}
+	k.tokens[region] = auth
+	k.expires[region] = aws.TimeValue(data.ExpiresAt).Add(-refreshBefore)
+	return auth, nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// exchangeForACRRefreshToken trades an AAD access token for host for an ACR
+// refresh token, per ACR's token exchange protocol:
+// https://<host>/oauth2/exchange.
+func exchangeForACRRefreshToken(host, aadAccessToken string) (string, error) {
+	u := url.URL{Scheme: "https", Host: host, Path: "/oauth2/exchange"}
+
+	form := url.Values{}
+	form.Set("grant_type", "access_token")
+	form.Set("service", host)
+	form.Set("access_token", aadAccessToken)
+
+	resp, err := http.PostForm(u.String(), form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("azure: token exchange with %s failed: %s: %s", host, resp.Status, string(body))
+	}
+
+	var parsed struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", err
+	}
+	return parsed.RefreshToken, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package azure implements the authn.Keychain interface for Azure Container
+// Registry (*.azurecr.io), exchanging an Azure Active Directory access token
+// (from the VM/managed-identity metadata endpoint) for an ACR refresh token.
+package azure
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+
+	"github.com/google/go-containerregistry/authn"
+	"github.com/google/go-containerregistry/name"
+)
+
+// Keychain implements authn.Keychain for Azure Container Registry hosts.
+var Keychain authn.Keychain = &acrKeychain{}
+
+// managementEndpoint is the resource we request an AAD token for before
+// exchanging it with ACR.
+const managementEndpoint = "https://management.azure.com/"
+
+// refreshBefore mirrors the aws keychain: refresh a bit ahead of the token's
+// reported expiry rather than right at it.
+const refreshBefore = 5 * time.Minute
+
+type acrKeychain struct {
+	mu      sync.Mutex
+	tokens  map[string]*authn.Basic
+	expires map[string]time.Time
+}
+
+func (k *acrKeychain) Resolve(reg name.Registry) (authn.Authenticator, error) {
+	host := reg.Name()
+	if !strings.HasSuffix(host, ".azurecr.io") {
+		return authn.Anonymous, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.tokens == nil {
+		k.tokens = map[string]*authn.Basic{}
+		k.expires = map[string]time.Time{}
+	}
+	if tok, ok := k.tokens[host]; ok && time.Now().Before(k.expires[host]) {
+		return tok, nil
+	}
+
+	spToken, err := managedIdentityToken()
+	if err != nil {
+		return authn.Anonymous, nil
+	}
+	if err := spToken.Refresh(); err != nil {
+		return authn.Anonymous, nil
+	}
+
+	refreshToken, err := exchangeForACRRefreshToken(host, spToken.OAuthToken())
+	if err != nil {
+		return authn.Anonymous, nil
+	}
+
+	basic := &authn.Basic{Username: "00000000-0000-0000-0000-000000000000", Password: refreshToken}
+	k.tokens[host] = basic
+	k.expires[host] = time.Time(spToken.Token().Expires()).Add(-refreshBefore)
+	return basic, nil
+}
+
+// managedIdentityToken requests an AAD token for the management endpoint
+// from the VM's managed identity endpoint.
+func managedIdentityToken() (*adal.ServicePrincipalToken, error) {
+	msiEndpoint, err := adal.GetMSIVMEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	return adal.NewServicePrincipalTokenFromMSI(msiEndpoint, managementEndpoint)
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package google implements the authn.Keychain interface for GCR-compatible
+// registries (gcr.io, *.gcr.io, *-docker.pkg.dev), using Application Default
+// Credentials (the GCE/GKE metadata server, gcloud's cached credentials, or
+// $GOOGLE_APPLICATION_CREDENTIALS) to mint a short-lived access token.
+package google
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	"github.com/google/go-containerregistry/authn"
+	"github.com/google/go-containerregistry/name"
+)
+
+// Keychain implements authn.Keychain for GCR-compatible registries.
+var Keychain authn.Keychain = &googleKeychain{}
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+type googleKeychain struct {
+	once sync.Once
+	ts   oauth2.TokenSource
+	err  error
+}
+
+// tokenSource lazily resolves Application Default Credentials and wraps them
+// in a ReuseTokenSource, so repeated calls only mint a new token once the
+// previous one is near expiry.
+func (k *googleKeychain) tokenSource() (oauth2.TokenSource, error) {
+	k.once.Do(func() {
+		src, err := google.DefaultTokenSource(context.Background(), cloudPlatformScope)
+		if err != nil {
+			k.err = err
+			return
+		}
+		k.ts = oauth2.ReuseTokenSource(nil, src)
+	})
+	return k.ts, k.err
+}
+
+func (k *googleKeychain) Resolve(reg name.Registry) (authn.Authenticator, error) {
+	if !isGCR(reg.Name()) {
+		return authn.Anonymous, nil
+	}
+
+	ts, err := k.tokenSource()
+	if err != nil {
+		// No usable Google credentials in this environment; let the caller
+		// fall back to another keychain.
+		return authn.Anonymous, nil
+	}
+	tok, err := ts.Token()
+	if err != nil {
+		return authn.Anonymous, nil
+	}
+	return &tokenAuthenticator{token: tok}, nil
+}
+
+func isGCR(host string) bool {
+	return host == "gcr.io" || strings.HasSuffix(host, ".gcr.io") || strings.HasSuffix(host, "-docker.pkg.dev")
+}
+
+type tokenAuthenticator struct {
+	token *oauth2.Token
+}
+
+func (t *tokenAuthenticator) Authorization() (string, error) {
+	return "Bearer " + t.token.AccessToken, nil
+}
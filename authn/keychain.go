@@ -0,0 +1,191 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package authn resolves registry credentials from ~/.docker/config.json and
+// its credential helpers.
+package authn
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/name"
+)
+
+// Authenticator is used to authenticate a registry request.
+type Authenticator interface {
+	// Authorization returns the value to use in an HTTP Authorization
+	// header for the request, or "" to send no header at all.
+	Authorization() (string, error)
+}
+
+// Keychain resolves a registry to an Authenticator to use for requests
+// against it.
+type Keychain interface {
+	Resolve(name.Registry) (Authenticator, error)
+}
+
+// DefaultKeychain parses ~/.docker/config.json (or $DOCKER_CONFIG/config.json)
+// the same way the docker CLI does: an exact "credHelpers" entry wins, then
+// "credStore", then a matching "auths" entry; otherwise it returns Anonymous.
+var DefaultKeychain Keychain = &defaultKeychain{}
+
+type defaultKeychain struct{}
+
+// config mirrors the subset of ~/.docker/config.json we care about.
+type config struct {
+	CredHelpers map[string]string    `json:"credHelpers,omitempty"`
+	CredStore   string               `json:"credStore,omitempty"`
+	Auths       map[string]authEntry `json:"auths,omitempty"`
+}
+
+type authEntry struct {
+	Auth     string `json:"auth,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// configDir returns $DOCKER_CONFIG if set, or ~/.docker otherwise.
+func configDir() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".docker"), nil
+}
+
+// load reads dir/config.json, treating a missing or malformed file as an
+// empty (all-anonymous) config rather than an error.
+func load(dir string) (*config, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return &config{}, nil
+	}
+	cfg := &config{}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return &config{}, nil
+	}
+	return cfg, nil
+}
+
+func (dk *defaultKeychain) Resolve(reg name.Registry) (Authenticator, error) {
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, helperName := range cfg.CredHelpers {
+		if matchesRegistry(key, reg) {
+			return &helper{name: helperName, domain: reg}, nil
+		}
+	}
+	if cfg.CredStore != "" {
+		return &helper{name: cfg.CredStore, domain: reg}, nil
+	}
+	for key, entry := range cfg.Auths {
+		if !matchesRegistry(key, reg) {
+			continue
+		}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("authn: decoding auth for %s: %v", key, err)
+			}
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("authn: malformed auth entry for %s", key)
+			}
+			return &Basic{Username: parts[0], Password: parts[1]}, nil
+		}
+		if entry.Username != "" || entry.Password != "" {
+			return &Basic{Username: entry.Username, Password: entry.Password}, nil
+		}
+	}
+
+	// Nothing in the config file is configured for reg; explicitly report
+	// "no credentials" so callers like MultiKeychain can tell that apart
+	// from a real (if empty) Authenticator and fall through to another
+	// Keychain.
+	return Anonymous, nil
+}
+
+// matchesRegistry reports whether the config key (a bare host, a URL, or a
+// URL with a /v1/ or /v2/ suffix) refers to reg.
+func matchesRegistry(key string, reg name.Registry) bool {
+	if !strings.Contains(key, "://") {
+		key = "https://" + key
+	}
+	u, err := url.Parse(key)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return u.Host == reg.Name()
+}
+
+// Basic implements Authenticator for a plain username and password.
+type Basic struct {
+	Username string
+	Password string
+}
+
+// Authorization implements Authenticator.
+func (b *Basic) Authorization() (string, error) {
+	delimited := b.Username + ":" + b.Password
+	encoded := base64.StdEncoding.EncodeToString([]byte(delimited))
+	return "Basic " + encoded, nil
+}
+
+// helper invokes a docker credential helper binary (docker-credential-<name>)
+// to resolve credentials for domain.
+type helper struct {
+	name   string
+	domain name.Registry
+}
+
+// Authorization implements Authenticator.
+func (h *helper) Authorization() (string, error) {
+	cmd := exec.Command(fmt.Sprintf("docker-credential-%s", h.name), "get")
+	cmd.Stdin = strings.NewReader(h.domain.Name())
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("authn: invoking %s: %v", cmd.Path, err)
+	}
+
+	var resp struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return "", fmt.Errorf("authn: parsing %s output: %v", cmd.Path, err)
+	}
+	if resp.Username == "<token>" {
+		return "Bearer " + resp.Secret, nil
+	}
+	b := &Basic{Username: resp.Username, Password: resp.Secret}
+	return b.Authorization()
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import "github.com/google/go-containerregistry/name"
+
+// Anonymous is a sentinel Authenticator that explicitly requests no
+// credentials be sent. It lets a Keychain tell a caller "no auth configured
+// for this registry" apart from an Authenticator that happens to resolve to
+// empty credentials.
+var Anonymous Authenticator = &anonymousAuthenticator{}
+
+type anonymousAuthenticator struct{}
+
+func (a *anonymousAuthenticator) Authorization() (string, error) {
+	return "", nil
+}
+
+// multiKeychain composes a list of keychains into one, trying each in turn
+// until one returns something other than Anonymous.
+type multiKeychain struct {
+	keychains []Keychain
+}
+
+// NewMultiKeychain composes a list of keychains into one. Resolve walks the
+// keychains in order and returns the first non-anonymous Authenticator, so
+// that, e.g., a registry-specific cloud keychain can be tried before falling
+// back to DefaultKeychain.
+func NewMultiKeychain(keychains ...Keychain) Keychain {
+	return &multiKeychain{keychains: keychains}
+}
+
+func (mk *multiKeychain) Resolve(reg name.Registry) (Authenticator, error) {
+	for _, kc := range mk.keychains {
+		auth, err := kc.Resolve(reg)
+		if err != nil {
+			return nil, err
+		}
+		if auth != Anonymous {
+			return auth, nil
+		}
+	}
+	return Anonymous, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"testing"
+
+	"github.com/google/go-containerregistry/name"
+)
+
+// stubKeychain resolves every registry to a fixed Authenticator, so tests can
+// tell whether MultiKeychain fell through to it.
+type stubKeychain struct {
+	auth Authenticator
+}
+
+func (s *stubKeychain) Resolve(name.Registry) (Authenticator, error) {
+	return s.auth, nil
+}
+
+func TestMultiKeychainFallsThroughAnonymousDefault(t *testing.T) {
+	setupConfigDir() // Empty config dir: DefaultKeychain resolves to Anonymous.
+
+	want := &Basic{Username: "foo", Password: "bar"}
+	mkc := NewMultiKeychain(DefaultKeychain, &stubKeychain{auth: want})
+
+	got, err := mkc.Resolve(testRegistry)
+	if err != nil {
+		t.Fatalf("Resolve() = %v", err)
+	}
+	if got != Authenticator(want) {
+		t.Errorf("Resolve(); got %v, want %v", got, want)
+	}
+}
+
+func TestMultiKeychainPrefersFirstNonAnonymous(t *testing.T) {
+	setupConfigFile(`{"auths": {"https://test.io/v1/": {"username": "foo", "password": "bar"}}}`)
+
+	mkc := NewMultiKeychain(DefaultKeychain, &stubKeychain{auth: Anonymous})
+
+	got, err := mkc.Resolve(testRegistry)
+	if err != nil {
+		t.Fatalf("Resolve() = %v", err)
+	}
+	auth, err := got.Authorization()
+	if err != nil {
+		t.Fatalf("Authorization() = %v", err)
+	}
+	if want := "Basic Zm9vOmJhcg=="; auth != want {
+		t.Errorf("Authorization(); got %v, want %v", auth, want)
+	}
+}
+
+func TestMultiKeychainAllAnonymous(t *testing.T) {
+	setupConfigDir()
+
+	mkc := NewMultiKeychain(DefaultKeychain, &stubKeychain{auth: Anonymous})
+
+	got, err := mkc.Resolve(testRegistry)
+	if err != nil {
+		t.Fatalf("Resolve() = %v", err)
+	}
+	if got != Anonymous {
+		t.Errorf("Resolve(); got %v, want Anonymous", got)
+	}
+}
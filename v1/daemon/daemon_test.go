@@ -0,0 +1,244 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/google/go-containerregistry/name"
+	"github.com/google/go-containerregistry/v1"
+	"github.com/google/go-containerregistry/v1/tarball"
+	gtypes "github.com/google/go-containerregistry/v1/types"
+)
+
+// fakeClient implements dockerClient with per-call hooks, so tests can drive
+// Image and Write without a live dockerd.
+type fakeClient struct {
+	imageSave func(ctx context.Context, imageIDs []string) (io.ReadCloser, error)
+	imageLoad func(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error)
+}
+
+func (f *fakeClient) ImageSave(ctx context.Context, imageIDs []string) (io.ReadCloser, error) {
+	return f.imageSave(ctx, imageIDs)
+}
+
+func (f *fakeClient) ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error) {
+	return f.imageLoad(ctx, input, quiet)
+}
+
+// fakeLayer is a v1.Layer over a fixed, already-uncompressed blob of bytes.
+type fakeLayer struct {
+	uncompressed []byte
+	diffID       v1.Hash
+}
+
+func newFakeLayer(uncompressed []byte) (*fakeLayer, error) {
+	diffID, _, err := v1.SHA256(ioutil.NopCloser(bytes.NewReader(uncompressed)))
+	if err != nil {
+		return nil, err
+	}
+	return &fakeLayer{uncompressed: uncompressed, diffID: diffID}, nil
+}
+
+func (l *fakeLayer) Digest() (v1.Hash, error) { return l.diffID, nil }
+func (l *fakeLayer) DiffID() (v1.Hash, error) { return l.diffID, nil }
+func (l *fakeLayer) Size() (int64, error)     { return int64(len(l.uncompressed)), nil }
+func (l *fakeLayer) MediaType() (gtypes.MediaType, error) {
+	return gtypes.DockerLayer, nil
+}
+func (l *fakeLayer) Compressed() (io.ReadCloser, error) { return l.Uncompressed() }
+func (l *fakeLayer) Uncompressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.uncompressed)), nil
+}
+
+// fakeImage is a minimal v1.Image backed by a single fakeLayer.
+type fakeImage struct {
+	config []byte
+	digest v1.Hash
+	layers []v1.Layer
+}
+
+func (i *fakeImage) Layers() ([]v1.Layer, error) { return i.layers, nil }
+func (i *fakeImage) MediaType() (gtypes.MediaType, error) {
+	return gtypes.DockerManifestSchema2, nil
+}
+func (i *fakeImage) Size() (int64, error)                    { return 0, nil }
+func (i *fakeImage) ConfigName() (v1.Hash, error)            { return v1.Hash{}, nil }
+func (i *fakeImage) ConfigFile() (*v1.ConfigFile, error)     { return nil, nil }
+func (i *fakeImage) RawConfigFile() ([]byte, error)          { return i.config, nil }
+func (i *fakeImage) Digest() (v1.Hash, error)                { return i.digest, nil }
+func (i *fakeImage) Manifest() (*v1.Manifest, error)         { return nil, nil }
+func (i *fakeImage) RawManifest() ([]byte, error)            { return nil, nil }
+func (i *fakeImage) LayerByDigest(v1.Hash) (v1.Layer, error) { return nil, nil }
+func (i *fakeImage) LayerByDiffID(v1.Hash) (v1.Layer, error) { return nil, nil }
+
+func newFakeImage(t *testing.T) (v1.Image, *fakeLayer) {
+	t.Helper()
+	layer, err := newFakeLayer([]byte("hello layer\n"))
+	if err != nil {
+		t.Fatalf("newFakeLayer() = %v", err)
+	}
+	config := []byte(fmt.Sprintf(`{"rootfs":{"diff_ids":["%s"]}}`, layer.diffID))
+	digest, _, err := v1.SHA256(ioutil.NopCloser(bytes.NewReader(config)))
+	if err != nil {
+		t.Fatalf("SHA256() = %v", err)
+	}
+	return &fakeImage{config: config, digest: digest, layers: []v1.Layer{layer}}, layer
+}
+
+// tarballFor serializes img as a `docker save` tarball the way dockerd's
+// ImageSave would, for a fake ImageSave to hand back.
+func tarballFor(t *testing.T, ref name.Reference, img v1.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := tarball.Write(ref, img, &buf); err != nil {
+		t.Fatalf("tarball.Write() = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestImageRoundTrip(t *testing.T) {
+	img, layer := newFakeImage(t)
+	tag, err := name.NewTag("test.io/repo:latest", name.WeakValidation)
+	if err != nil {
+		t.Fatalf("NewTag() = %v", err)
+	}
+	saved := tarballFor(t, tag, img)
+
+	client := &fakeClient{
+		imageSave: func(ctx context.Context, imageIDs []string) (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(saved)), nil
+		},
+	}
+
+	got, err := Image(tag, WithClient(client))
+	if err != nil {
+		t.Fatalf("Image() = %v", err)
+	}
+
+	gotLayers, err := got.Layers()
+	if err != nil {
+		t.Fatalf("Layers() = %v", err)
+	}
+	if len(gotLayers) != 1 {
+		t.Fatalf("len(Layers()) = %d, want 1", len(gotLayers))
+	}
+	rc, err := gotLayers[0].Uncompressed()
+	if err != nil {
+		t.Fatalf("Uncompressed() = %v", err)
+	}
+	defer rc.Close()
+	gotBytes, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll() = %v", err)
+	}
+	if !bytes.Equal(gotBytes, layer.uncompressed) {
+		t.Errorf("layer contents; got %q, want %q", gotBytes, layer.uncompressed)
+	}
+}
+
+func TestImageSaveError(t *testing.T) {
+	want := errors.New("dockerd is down")
+	client := &fakeClient{
+		imageSave: func(ctx context.Context, imageIDs []string) (io.ReadCloser, error) {
+			return nil, want
+		},
+	}
+	tag, err := name.NewTag("test.io/repo:latest", name.WeakValidation)
+	if err != nil {
+		t.Fatalf("NewTag() = %v", err)
+	}
+
+	if _, err := Image(tag, WithClient(client)); err != want {
+		t.Errorf("Image() = %v, want %v", err, want)
+	}
+}
+
+func TestWriteSuccess(t *testing.T) {
+	img, _ := newFakeImage(t)
+	tag, err := name.NewTag("test.io/repo:latest", name.WeakValidation)
+	if err != nil {
+		t.Fatalf("NewTag() = %v", err)
+	}
+
+	client := &fakeClient{
+		imageLoad: func(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error) {
+			io.Copy(ioutil.Discard, input)
+			body := `{"stream":"Loaded image\n"}` + "\n"
+			return types.ImageLoadResponse{Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+
+	got, err := Write(tag, img, WithClient(client))
+	if err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	want, err := img.Digest()
+	if err != nil {
+		t.Fatalf("Digest() = %v", err)
+	}
+	if got != want.String() {
+		t.Errorf("Write() = %v, want %v", got, want.String())
+	}
+}
+
+func TestWriteReportsDaemonError(t *testing.T) {
+	img, _ := newFakeImage(t)
+	tag, err := name.NewTag("test.io/repo:latest", name.WeakValidation)
+	if err != nil {
+		t.Fatalf("NewTag() = %v", err)
+	}
+
+	client := &fakeClient{
+		imageLoad: func(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error) {
+			io.Copy(ioutil.Discard, input)
+			body := `{"stream":"step 1\n"}` + "\n" + `{"error":"failed to process tar"}` + "\n"
+			return types.ImageLoadResponse{Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+		},
+	}
+
+	_, err = Write(tag, img, WithClient(client))
+	if err == nil || !strings.Contains(err.Error(), "failed to process tar") {
+		t.Errorf("Write() = %v, want error containing %q", err, "failed to process tar")
+	}
+}
+
+func TestWriteImageLoadError(t *testing.T) {
+	img, _ := newFakeImage(t)
+	tag, err := name.NewTag("test.io/repo:latest", name.WeakValidation)
+	if err != nil {
+		t.Fatalf("NewTag() = %v", err)
+	}
+	want := errors.New("connection refused")
+
+	client := &fakeClient{
+		imageLoad: func(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error) {
+			io.Copy(ioutil.Discard, input)
+			return types.ImageLoadResponse{}, want
+		},
+	}
+
+	if _, err := Write(tag, img, WithClient(client)); err != want {
+		t.Errorf("Write() = %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package daemon provides methods for reading and writing images to and from
+// the Docker daemon.
+package daemon
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+
+	"github.com/google/go-containerregistry/name"
+	"github.com/google/go-containerregistry/v1"
+	"github.com/google/go-containerregistry/v1/tarball"
+)
+
+// Image accesses a given image reference from the Docker daemon.
+func Image(ref name.Reference, options ...Option) (v1.Image, error) {
+	o, err := makeOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := o.client.ImageSave(o.ctx, []string{ref.String()})
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// ImageSave's ReadCloser can only be drained once, but tarball.Image's
+	// opener may be invoked more than once, so spool it to disk. The temp
+	// file is removed once the returned image (and so tf, via the opener
+	// closure below) is no longer reachable.
+	tf, err := spoolToTempFile(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	return tarball.Image(func() (io.ReadCloser, error) {
+		return os.Open(tf.path)
+	}, nil)
+}
+
+// tempFile is a spooled copy of a daemon image tarball, removed from disk by
+// its finalizer once nothing references it any longer.
+type tempFile struct {
+	path string
+}
+
+// spoolToTempFile copies r to a new temp file and arranges for that file to
+// be removed once the returned handle is garbage collected.
+func spoolToTempFile(r io.Reader) (*tempFile, error) {
+	f, err := ioutil.TempFile("", "daemon-image-")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	tf := &tempFile{path: f.Name()}
+	runtime.SetFinalizer(tf, func(t *tempFile) {
+		os.Remove(t.path)
+	})
+	return tf, nil
+}
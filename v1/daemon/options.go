@@ -0,0 +1,74 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// dockerClient is the subset of *client.Client that Image and Write need, so
+// that tests can drive them against a fake instead of a live dockerd.
+type dockerClient interface {
+	ImageSave(ctx context.Context, imageIDs []string) (io.ReadCloser, error)
+	ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error)
+}
+
+type options struct {
+	ctx    context.Context
+	client dockerClient
+}
+
+// Option is a functional option for daemon.Image and daemon.Write.
+type Option func(*options) error
+
+// WithContext sets the context used for all calls made to the Docker daemon.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) error {
+		o.ctx = ctx
+		return nil
+	}
+}
+
+// WithClient sets the Docker client used to talk to the daemon, instead of
+// the one constructed from the environment.
+func WithClient(c dockerClient) Option {
+	return func(o *options) error {
+		o.client = c
+		return nil
+	}
+}
+
+func makeOptions(opts ...Option) (*options, error) {
+	o := &options{
+		ctx: context.Background(),
+	}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	if o.client == nil {
+		c, err := client.NewEnvClient()
+		if err != nil {
+			return nil, err
+		}
+		o.client = c
+	}
+	return o, nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/google/go-containerregistry/name"
+	"github.com/google/go-containerregistry/v1"
+	"github.com/google/go-containerregistry/v1/tarball"
+)
+
+// progressMessage is a single line of the newline-delimited JSON progress
+// stream ImageLoad's response body carries; we only care enough to drain it
+// and fail on any error the daemon reports.
+type progressMessage struct {
+	Stream string `json:"stream,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Write saves the image into the daemon as the given tag, and returns the
+// digest of the uploaded image.
+func Write(ref name.Tag, img v1.Image, options ...Option) (string, error) {
+	o, err := makeOptions(options...)
+	if err != nil {
+		return "", err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(tarball.Write(ref, img, pw))
+	}()
+
+	resp, err := o.client.ImageLoad(o.ctx, pr, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// ImageLoad's response body is newline-delimited JSON progress messages,
+	// not a single JSON object.
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg progressMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		if msg.Error != "" {
+			return "", fmt.Errorf("daemon: loading image: %s", msg.Error)
+		}
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return "", err
+	}
+	return digest.String(), nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mutate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/google/go-containerregistry/v1"
+	"github.com/google/go-containerregistry/v1/types"
+)
+
+// staticImage is a v1.Image assembled directly from a config and a set of
+// layers, rather than one derived lazily from a remote or on-disk source.
+type staticImage struct {
+	rawConfig   []byte
+	config      *v1.ConfigFile
+	layers      []v1.Layer
+	manifest    *v1.Manifest
+	rawManifest []byte
+}
+
+var _ v1.Image = (*staticImage)(nil)
+
+// newStaticImage assembles a v1.Image from cfg and layers, computing the
+// config digest and a fresh manifest from the layers' own digests and sizes.
+func newStaticImage(cfg *v1.ConfigFile, layers []v1.Layer) (v1.Image, error) {
+	rawConfig, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfgDigest, cfgSize, err := v1.SHA256(ioutil.NopCloser(bytes.NewReader(rawConfig)))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.DockerManifestSchema2,
+		Config: v1.Descriptor{
+			MediaType: types.DockerConfigJSON,
+			Size:      cfgSize,
+			Digest:    cfgDigest,
+		},
+	}
+	for _, l := range layers {
+		d, err := l.Digest()
+		if err != nil {
+			return nil, err
+		}
+		size, err := l.Size()
+		if err != nil {
+			return nil, err
+		}
+		mt, err := l.MediaType()
+		if err != nil {
+			return nil, err
+		}
+		m.Layers = append(m.Layers, v1.Descriptor{MediaType: mt, Size: size, Digest: d})
+	}
+
+	rawManifest, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &staticImage{
+		rawConfig:   rawConfig,
+		config:      cfg,
+		layers:      layers,
+		manifest:    m,
+		rawManifest: rawManifest,
+	}, nil
+}
+
+func (i *staticImage) Layers() ([]v1.Layer, error) { return i.layers, nil }
+
+func (i *staticImage) MediaType() (types.MediaType, error) { return i.manifest.MediaType, nil }
+
+func (i *staticImage) Size() (int64, error) {
+	_, size, err := v1.SHA256(ioutil.NopCloser(bytes.NewReader(i.rawManifest)))
+	return size, err
+}
+
+func (i *staticImage) ConfigName() (v1.Hash, error) { return i.manifest.Config.Digest, nil }
+
+func (i *staticImage) ConfigFile() (*v1.ConfigFile, error) { return i.config, nil }
+
+func (i *staticImage) RawConfigFile() ([]byte, error) { return i.rawConfig, nil }
+
+func (i *staticImage) Digest() (v1.Hash, error) {
+	d, _, err := v1.SHA256(ioutil.NopCloser(bytes.NewReader(i.rawManifest)))
+	return d, err
+}
+
+func (i *staticImage) Manifest() (*v1.Manifest, error) { return i.manifest, nil }
+
+func (i *staticImage) RawManifest() ([]byte, error) { return i.rawManifest, nil }
+
+func (i *staticImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	for _, l := range i.layers {
+		d, err := l.Digest()
+		if err != nil {
+			return nil, err
+		}
+		if d == h {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("mutate: layer with digest %s not found", h)
+}
+
+func (i *staticImage) LayerByDiffID(h v1.Hash) (v1.Layer, error) {
+	for _, l := range i.layers {
+		d, err := l.DiffID()
+		if err != nil {
+			return nil, err
+		}
+		if d == h {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("mutate: layer with diffID %s not found", h)
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mutate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/google/go-containerregistry/v1"
+	"github.com/google/go-containerregistry/v1/types"
+)
+
+// rewrittenLayer is a fully materialized v1.Layer produced by rewriting
+// another layer's tar headers; its digest and size are computed once, up
+// front, rather than lazily.
+type rewrittenLayer struct {
+	uncompressed []byte
+	compressed   []byte
+	diffID       v1.Hash
+	digest       v1.Hash
+	mediaType    types.MediaType
+}
+
+var _ v1.Layer = (*rewrittenLayer)(nil)
+
+// rewriteLayerTimes reads every entry of base's uncompressed tar, rewrites
+// its ModTime/AccessTime/ChangeTime to t, zeroes Uname/Gname, and clamps
+// Uid/Gid to 0 unless whitelisted, returning the result as a new layer.
+func rewriteLayerTimes(base v1.Layer, t time.Time, o *timeOpts) (v1.Layer, error) {
+	mt, err := base.MediaType()
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := base.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var raw bytes.Buffer
+	if err := rewriteTarHeaders(rc, &raw, t, o); err != nil {
+		return nil, err
+	}
+
+	return newRewrittenLayer(raw.Bytes(), mt)
+}
+
+func newRewrittenLayer(uncompressed []byte, mt types.MediaType) (*rewrittenLayer, error) {
+	diffID, _, err := v1.SHA256(ioutil.NopCloser(bytes.NewReader(uncompressed)))
+	if err != nil {
+		return nil, err
+	}
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	if _, err := zw.Write(uncompressed); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	digest, _, err := v1.SHA256(ioutil.NopCloser(bytes.NewReader(gz.Bytes())))
+	if err != nil {
+		return nil, err
+	}
+
+	return &rewrittenLayer{
+		uncompressed: uncompressed,
+		compressed:   gz.Bytes(),
+		diffID:       diffID,
+		digest:       digest,
+		mediaType:    mt,
+	}, nil
+}
+
+// rewriteTarHeaders copies the tar stream r into w, rewriting each entry's
+// header per t and o along the way.
+func rewriteTarHeaders(r io.Reader, w io.Writer, t time.Time, o *timeOpts) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Headers read back as USTAR can't carry AccessTime/ChangeTime.
+		hdr.Format = tar.FormatPAX
+		hdr.ModTime = t
+		hdr.AccessTime = t
+		hdr.ChangeTime = t
+		hdr.Uname = ""
+		hdr.Gname = ""
+		if !o.uidGidWhitelist[hdr.Uid] {
+			hdr.Uid = 0
+		}
+		if !o.uidGidWhitelist[hdr.Gid] {
+			hdr.Gid = 0
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *rewrittenLayer) Digest() (v1.Hash, error) { return l.digest, nil }
+func (l *rewrittenLayer) DiffID() (v1.Hash, error) { return l.diffID, nil }
+func (l *rewrittenLayer) Size() (int64, error)     { return int64(len(l.compressed)), nil }
+func (l *rewrittenLayer) MediaType() (types.MediaType, error) {
+	return l.mediaType, nil
+}
+
+func (l *rewrittenLayer) Compressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.compressed)), nil
+}
+
+func (l *rewrittenLayer) Uncompressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.uncompressed)), nil
+}
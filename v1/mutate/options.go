@@ -0,0 +1,53 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mutate
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// timeOpts holds the options accumulated from a list of TimeOption.
+type timeOpts struct {
+	uidGidWhitelist map[int]bool
+}
+
+// TimeOption customizes Time's rewriting of layer tar headers.
+type TimeOption func(*timeOpts)
+
+// WithUIDGIDWhitelist keeps the uids and gids in ids as-is, instead of
+// clamping them to 0, when Time rewrites layer tar headers.
+func WithUIDGIDWhitelist(ids map[int]bool) TimeOption {
+	return func(o *timeOpts) {
+		o.uidGidWhitelist = ids
+	}
+}
+
+// SourceDateEpoch returns time.Unix(epoch, 0) per $SOURCE_DATE_EPOCH if it's
+// set to a valid integer, or def otherwise. This is the environment variable
+// reproducible-build tooling already uses to pin build timestamps; see
+// https://reproducible-builds.org/specs/source-date-epoch/.
+func SourceDateEpoch(def time.Time) time.Time {
+	v, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok {
+		return def
+	}
+	secs, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return def
+	}
+	return time.Unix(secs, 0)
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mutate
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/go-containerregistry/v1"
+)
+
+// Time returns a new image with every layer's tar headers' ModTime,
+// AccessTime, and ChangeTime set to t, ownership zeroed (see
+// WithUIDGIDWhitelist), and the config file's non-deterministic fields
+// (history[*].created, created, and the container/container_config blocks
+// that capture build-time state) stripped, recomputing layer and config
+// digests to match. This gives supply-chain-conscious callers a bit-for-bit
+// reproducible image from otherwise non-deterministic build inputs.
+func Time(img v1.Image, t time.Time, opts ...TimeOption) (v1.Image, error) {
+	o := &timeOpts{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+	newLayers := make([]v1.Layer, len(layers))
+	diffIDs := make([]v1.Hash, len(layers))
+	for idx, l := range layers {
+		rl, err := rewriteLayerTimes(l, t, o)
+		if err != nil {
+			return nil, err
+		}
+		diffID, err := rl.DiffID()
+		if err != nil {
+			return nil, err
+		}
+		newLayers[idx] = rl
+		diffIDs[idx] = diffID
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err = deepCopyConfigFile(cfg)
+	if err != nil {
+		return nil, err
+	}
+	stripNonDeterministic(cfg, t)
+	cfg.RootFS.DiffIDs = diffIDs
+
+	return newStaticImage(cfg, newLayers)
+}
+
+// CreatedAt returns a new image whose config's Created timestamp is t,
+// without touching layer contents.
+func CreatedAt(img v1.Image, t time.Time) (v1.Image, error) {
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err = deepCopyConfigFile(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Created = v1.Time{Time: t}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+	return newStaticImage(cfg, layers)
+}
+
+// stripNonDeterministic rewrites the parts of cfg that otherwise vary from
+// build to build without reflecting a real change to the image: per-history
+// timestamps, the image's own Created time, and the build-time container
+// bookkeeping Docker leaves behind.
+func stripNonDeterministic(cfg *v1.ConfigFile, t time.Time) {
+	cfg.Created = v1.Time{Time: t}
+	cfg.Container = ""
+	cfg.ContainerConfig = v1.Config{}
+	for i := range cfg.History {
+		cfg.History[i].Created = v1.Time{Time: t}
+	}
+}
+
+// deepCopyConfigFile returns an independent copy of cfg, so that callers
+// building a new image never mutate the v1.ConfigFile the caller's img still
+// references.
+func deepCopyConfigFile(cfg *v1.ConfigFile) (*v1.ConfigFile, error) {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	out := &v1.ConfigFile{}
+	if err := json.Unmarshal(b, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mutate
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/v1"
+	"github.com/google/go-containerregistry/v1/types"
+)
+
+// buildTar returns an uncompressed tar containing a single file entry with
+// the given name, modtime, and ownership, so tests can simulate two builds
+// that differ only in non-deterministic bookkeeping.
+func buildTar(t *testing.T, name string, modTime time.Time, uid, gid int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	content := []byte("same content\n")
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Size:    int64(len(content)),
+		ModTime: modTime,
+		Uid:     uid,
+		Gid:     gid,
+		Uname:   "someuser",
+		Gname:   "somegroup",
+	}); err != nil {
+		t.Fatalf("WriteHeader() = %v", err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// rawLayer is a v1.Layer over fixed, already-uncompressed tar bytes.
+type rawLayer struct {
+	uncompressed []byte
+}
+
+func (l *rawLayer) Digest() (v1.Hash, error)            { return v1.Hash{}, nil }
+func (l *rawLayer) DiffID() (v1.Hash, error)            { return v1.Hash{}, nil }
+func (l *rawLayer) Size() (int64, error)                { return int64(len(l.uncompressed)), nil }
+func (l *rawLayer) MediaType() (types.MediaType, error) { return types.DockerLayer, nil }
+func (l *rawLayer) Compressed() (io.ReadCloser, error)  { return nil, nil }
+func (l *rawLayer) Uncompressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.uncompressed)), nil
+}
+
+// buildImage returns a v1.Image wrapping a single layer built from tarBytes,
+// with a config whose Created/Container fields simulate build-time noise.
+func buildImage(t *testing.T, tarBytes []byte, created time.Time, container string) v1.Image {
+	t.Helper()
+	cfg := &v1.ConfigFile{
+		Created:   v1.Time{Time: created},
+		Container: container,
+		History:   []v1.History{{Created: v1.Time{Time: created}}},
+	}
+	img, err := newStaticImage(cfg, []v1.Layer{&rawLayer{uncompressed: tarBytes}})
+	if err != nil {
+		t.Fatalf("newStaticImage() = %v", err)
+	}
+	return img
+}
+
+func TestTimeIsIdempotent(t *testing.T) {
+	epoch := time.Unix(0, 0)
+	tarBytes := buildTar(t, "foo", time.Now(), 1000, 1000)
+	img := buildImage(t, tarBytes, time.Now(), "deadbeef")
+
+	first, err := Time(img, epoch)
+	if err != nil {
+		t.Fatalf("Time() = %v", err)
+	}
+	second, err := Time(img, epoch)
+	if err != nil {
+		t.Fatalf("Time() = %v", err)
+	}
+
+	d1, err := first.Digest()
+	if err != nil {
+		t.Fatalf("Digest() = %v", err)
+	}
+	d2, err := second.Digest()
+	if err != nil {
+		t.Fatalf("Digest() = %v", err)
+	}
+	if d1 != d2 {
+		t.Errorf("Time() is not idempotent: got %v and %v", d1, d2)
+	}
+}
+
+func TestTimeMasksBuildNoise(t *testing.T) {
+	epoch := time.Unix(0, 0)
+	tarA := buildTar(t, "foo", time.Unix(1000, 0), 1000, 1000)
+	tarB := buildTar(t, "foo", time.Unix(2000, 0), 2000, 2000)
+
+	imgA := buildImage(t, tarA, time.Unix(1000, 0), "containerA")
+	imgB := buildImage(t, tarB, time.Unix(2000, 0), "containerB")
+
+	outA, err := Time(imgA, epoch)
+	if err != nil {
+		t.Fatalf("Time() = %v", err)
+	}
+	outB, err := Time(imgB, epoch)
+	if err != nil {
+		t.Fatalf("Time() = %v", err)
+	}
+
+	dA, err := outA.Digest()
+	if err != nil {
+		t.Fatalf("Digest() = %v", err)
+	}
+	dB, err := outB.Digest()
+	if err != nil {
+		t.Fatalf("Digest() = %v", err)
+	}
+	if dA != dB {
+		t.Errorf("Time() did not mask build noise: got %v and %v", dA, dB)
+	}
+}
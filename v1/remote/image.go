@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 
 	"github.com/google/go-containerregistry/authn"
@@ -38,22 +39,72 @@ type remoteImage struct {
 	client       *http.Client
 	manifestLock sync.Mutex // Protects manifest
 	manifest     []byte
+	mediaType    types.MediaType
 	configLock   sync.Mutex // Protects config
 	config       []byte
 }
 
 var _ partial.CompressedImageCore = (*remoteImage)(nil)
 
-// Image accesses a given image reference over the provided transport, with the provided authentication.
-func Image(ref name.Reference, auth authn.Authenticator, t http.RoundTripper) (v1.Image, error) {
-	tr, err := transport.New(ref, auth, t, transport.PullScope)
+// acceptableImageMediaTypes is the set of media types we're willing to accept
+// when requesting a manifest, so that we can detect and walk manifest lists
+// and image indexes in addition to plain image manifests.
+var acceptableImageMediaTypes = []types.MediaType{
+	types.DockerManifestSchema2,
+	types.OCIManifestSchema1,
+	types.DockerManifestList,
+	types.OCIImageIndex,
+}
+
+// Image accesses a given image reference over the provided transport, with
+// the provided authentication.
+//
+// If the reference resolves to a manifest list or image index, the child
+// image matching WithPlatform is returned, falling back to linux/amd64 if no
+// platform is given.
+func Image(ref name.Reference, auth authn.Authenticator, t http.RoundTripper, opts ...ImageOption) (v1.Image, error) {
+	o := makeImageOpts(opts...)
+
+	var topts []transport.Option
+	if o.retry {
+		topts = append(topts, transport.WithRetry(o.retryOpts...))
+	}
+	tr, err := transport.New(ref, auth, t, transport.PullScope, topts...)
 	if err != nil {
 		return nil, err
 	}
-	return partial.CompressedToImage(&remoteImage{
+
+	ri := &remoteImage{
 		ref:    ref,
 		client: &http.Client{Transport: tr},
-	})
+	}
+
+	mt, err := ri.MediaType()
+	if err != nil {
+		return nil, err
+	}
+
+	if mt == types.DockerManifestList || mt == types.OCIImageIndex {
+		idx := &remoteIndex{
+			ref:       ref,
+			client:    ri.client,
+			manifest:  ri.manifest,
+			mediaType: mt,
+		}
+		return idx.Image(o.platform)
+	}
+
+	return partial.CompressedToImage(ri)
+}
+
+// acceptString joins a list of media types into a value suitable for an HTTP
+// Accept header.
+func acceptString(mts []types.MediaType) string {
+	accept := make([]string, len(mts))
+	for i, mt := range mts {
+		accept[i] = string(mt)
+	}
+	return strings.Join(accept, ",")
 }
 
 func (r *remoteImage) url(resource, identifier string) url.URL {
@@ -65,11 +116,14 @@ func (r *remoteImage) url(resource, identifier string) url.URL {
 }
 
 func (r *remoteImage) MediaType() (types.MediaType, error) {
-	// TODO(jonjohnsonjr): Determine this based on response.
-	return types.DockerManifestSchema2, nil
+	if r.mediaType == "" {
+		if _, err := r.RawManifest(); err != nil {
+			return "", err
+		}
+	}
+	return r.mediaType, nil
 }
 
-// TODO(jonjohnsonjr): Handle manifest lists.
 // TODO(jonjohnsonjr): DockerHub returns the manifest list's digest when it falls back to schema 2??
 func (r *remoteImage) RawManifest() ([]byte, error) {
 	r.manifestLock.Lock()
@@ -83,8 +137,7 @@ func (r *remoteImage) RawManifest() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	// TODO(jonjohnsonjr): Accept OCI manifest, manifest list, and image index.
-	req.Header.Set("Accept", string(types.DockerManifestSchema2))
+	req.Header.Set("Accept", acceptString(acceptableImageMediaTypes))
 	resp, err := r.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -115,6 +168,7 @@ func (r *remoteImage) RawManifest() ([]byte, error) {
 		return nil, fmt.Errorf("manifest digest: %s does not match Docker-Content-Digest: %s", digest, checksum)
 	}
 
+	r.mediaType = types.MediaType(resp.Header.Get("Content-Type"))
 	r.manifest = manifest
 	return r.manifest, nil
 }
@@ -0,0 +1,193 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/google/go-containerregistry/authn"
+	"github.com/google/go-containerregistry/name"
+	"github.com/google/go-containerregistry/v1"
+	"github.com/google/go-containerregistry/v1/partial"
+	"github.com/google/go-containerregistry/v1/remote/transport"
+	"github.com/google/go-containerregistry/v1/types"
+)
+
+// remoteIndex accesses a manifest list (or OCI image index) from a remote registry.
+type remoteIndex struct {
+	ref          name.Reference
+	client       *http.Client
+	manifestLock sync.Mutex // Protects manifest
+	manifest     []byte
+	mediaType    types.MediaType
+}
+
+// Index accesses a given manifest list (or OCI image index) reference over the
+// provided transport, with the provided authentication.
+func Index(ref name.Reference, auth authn.Authenticator, t http.RoundTripper) (v1.ImageIndex, error) {
+	tr, err := transport.New(ref, auth, t, transport.PullScope)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteIndex{
+		ref:    ref,
+		client: &http.Client{Transport: tr},
+	}, nil
+}
+
+func (r *remoteIndex) url(resource, identifier string) url.URL {
+	return url.URL{
+		Scheme: transport.Scheme(r.ref.Context().Registry),
+		Host:   r.ref.Context().RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/%s/%s", r.ref.Context().RepositoryStr(), resource, identifier),
+	}
+}
+
+func (r *remoteIndex) MediaType() (types.MediaType, error) {
+	if r.mediaType == "" {
+		if _, err := r.RawManifest(); err != nil {
+			return "", err
+		}
+	}
+	return r.mediaType, nil
+}
+
+func (r *remoteIndex) Digest() (v1.Hash, error) {
+	return partial.Digest(r)
+}
+
+func (r *remoteIndex) Size() (int64, error) {
+	return partial.Size(r)
+}
+
+func (r *remoteIndex) RawManifest() ([]byte, error) {
+	r.manifestLock.Lock()
+	defer r.manifestLock.Unlock()
+	if r.manifest != nil {
+		return r.manifest, nil
+	}
+
+	u := r.url("manifests", r.ref.Identifier())
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", acceptString(acceptableImageMediaTypes))
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkError(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	manifest, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, _, err := v1.SHA256(ioutil.NopCloser(bytes.NewReader(manifest)))
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the digest matches what we asked for, if pulling by digest.
+	if dgst, ok := r.ref.(name.Digest); ok {
+		if digest.String() != dgst.DigestStr() {
+			return nil, fmt.Errorf("manifest digest: %s does not match requested digest: %s", digest, dgst.DigestStr())
+		}
+	} else if checksum := resp.Header.Get("Docker-Content-Digest"); checksum != "" && checksum != digest.String() {
+		// When pulling by tag, we can only validate that the digest matches what the registry told us it should be.
+		return nil, fmt.Errorf("manifest digest: %s does not match Docker-Content-Digest: %s", digest, checksum)
+	}
+
+	r.mediaType = types.MediaType(resp.Header.Get("Content-Type"))
+	r.manifest = manifest
+	return r.manifest, nil
+}
+
+func (r *remoteIndex) IndexManifest() (*v1.IndexManifest, error) {
+	b, err := r.RawManifest()
+	if err != nil {
+		return nil, err
+	}
+	im := &v1.IndexManifest{}
+	if err := json.Unmarshal(b, im); err != nil {
+		return nil, err
+	}
+	return im, nil
+}
+
+// Image returns the child image of this index matching platform, falling
+// back to linux/amd64 when platform is the zero value.
+func (r *remoteIndex) Image(platform v1.Platform) (v1.Image, error) {
+	if platform.OS == "" && platform.Architecture == "" {
+		platform = v1.Platform{OS: "linux", Architecture: "amd64"}
+	}
+
+	im, err := r.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	for _, desc := range im.Manifests {
+		if desc.Platform == nil {
+			continue
+		}
+		if platformMatches(desc.Platform, platform) {
+			digestRef := r.ref.Context().Digest(desc.Digest.String())
+			return partial.CompressedToImage(&remoteImage{
+				ref:    digestRef,
+				client: r.client,
+			})
+		}
+	}
+	return nil, fmt.Errorf("no child manifest in %s matching platform %s", r.ref, platformString(platform))
+}
+
+// platformMatches reports whether a manifest descriptor's platform satisfies
+// the requested one. OS and Architecture must always match; Variant and
+// OSVersion are only compared when the caller asked for a specific value,
+// since many indexes omit them for entries where they don't apply.
+func platformMatches(have *v1.Platform, want v1.Platform) bool {
+	if have.OS != want.OS || have.Architecture != want.Architecture {
+		return false
+	}
+	if want.Variant != "" && have.Variant != want.Variant {
+		return false
+	}
+	if want.OSVersion != "" && have.OSVersion != want.OSVersion {
+		return false
+	}
+	return true
+}
+
+func platformString(p v1.Platform) string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	if p.OSVersion != "" {
+		s += " (" + p.OSVersion + ")"
+	}
+	return s
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"testing"
+
+	"github.com/google/go-containerregistry/v1"
+)
+
+func TestPlatformMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		have v1.Platform
+		want v1.Platform
+		ok   bool
+	}{{
+		name: "exact match",
+		have: v1.Platform{OS: "linux", Architecture: "amd64"},
+		want: v1.Platform{OS: "linux", Architecture: "amd64"},
+		ok:   true,
+	}, {
+		name: "different arch",
+		have: v1.Platform{OS: "linux", Architecture: "amd64"},
+		want: v1.Platform{OS: "linux", Architecture: "arm64"},
+		ok:   false,
+	}, {
+		name: "variant requested, not present",
+		have: v1.Platform{OS: "linux", Architecture: "arm"},
+		want: v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+		ok:   false,
+	}, {
+		name: "variant requested and matches",
+		have: v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+		want: v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+		ok:   true,
+	}, {
+		name: "variant requested but mismatches",
+		have: v1.Platform{OS: "linux", Architecture: "arm", Variant: "v6"},
+		want: v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+		ok:   false,
+	}, {
+		name: "no variant requested, entry has one",
+		have: v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+		want: v1.Platform{OS: "linux", Architecture: "arm"},
+		ok:   true,
+	}, {
+		name: "OSVersion requested and matches",
+		have: v1.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763.1879"},
+		want: v1.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763.1879"},
+		ok:   true,
+	}, {
+		name: "OSVersion requested but mismatches",
+		have: v1.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763.1879"},
+		want: v1.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.14393.3630"},
+		ok:   false,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := platformMatches(&test.have, test.want); got != test.ok {
+				t.Errorf("platformMatches(%+v, %+v) = %v, want %v", test.have, test.want, got, test.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"github.com/google/go-containerregistry/v1"
+	"github.com/google/go-containerregistry/v1/remote/transport"
+)
+
+// imageOpts holds the options accumulated from a list of ImageOption.
+type imageOpts struct {
+	platform  v1.Platform
+	retry     bool
+	retryOpts []transport.RetryOption
+}
+
+// ImageOption customizes how remote.Image resolves an image reference.
+type ImageOption func(*imageOpts)
+
+// WithPlatform selects the child of a manifest list or image index matching
+// platform, instead of the default linux/amd64.
+func WithPlatform(p v1.Platform) ImageOption {
+	return func(o *imageOpts) {
+		o.platform = p
+	}
+}
+
+// WithRetry wraps the underlying transport in a retrying RoundTripper (see
+// transport.NewRetry), so that transient registry errors don't fail the pull.
+// It is opt-in: without it, requests are attempted exactly once.
+func WithRetry(opts ...transport.RetryOption) ImageOption {
+	return func(o *imageOpts) {
+		o.retry = true
+		o.retryOpts = opts
+	}
+}
+
+func makeImageOpts(opts ...ImageOption) *imageOpts {
+	o := &imageOpts{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/authn"
+	"github.com/google/go-containerregistry/name"
+	"github.com/google/go-containerregistry/v1"
+	"github.com/google/go-containerregistry/v1/types"
+)
+
+// manifestFor builds a schema2 manifest referencing a dummy config blob of
+// the given size, so that otherwise-identical variants of an index's
+// children hash to distinct digests.
+func manifestFor(t *testing.T, configSize int64) (v1.Manifest, []byte, v1.Hash) {
+	t.Helper()
+	m := v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.DockerManifestSchema2,
+		Config: v1.Descriptor{
+			MediaType: types.DockerConfigJSON,
+			Size:      configSize,
+			Digest:    v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("0", 64)},
+		},
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("json.Marshal(manifest) = %v", err)
+	}
+	digest, _, err := v1.SHA256(ioutil.NopCloser(bytes.NewReader(b)))
+	if err != nil {
+		t.Fatalf("SHA256() = %v", err)
+	}
+	return m, b, digest
+}
+
+// TestImageSelectsChildByPlatform spins up a fake registry serving a
+// manifest list with two arm variants, and checks that remote.Image with
+// WithPlatform fetches the manifest matching that platform, not just the
+// first (or wrong-variant) entry.
+func TestImageSelectsChildByPlatform(t *testing.T) {
+	_, v6Bytes, v6Digest := manifestFor(t, 100)
+	_, v7Bytes, v7Digest := manifestFor(t, 200)
+
+	idx := v1.IndexManifest{
+		SchemaVersion: 2,
+		MediaType:     types.DockerManifestList,
+		Manifests: []v1.Descriptor{{
+			MediaType: types.DockerManifestSchema2,
+			Size:      int64(len(v6Bytes)),
+			Digest:    v6Digest,
+			Platform:  &v1.Platform{OS: "linux", Architecture: "arm", Variant: "v6"},
+		}, {
+			MediaType: types.DockerManifestSchema2,
+			Size:      int64(len(v7Bytes)),
+			Digest:    v7Digest,
+			Platform:  &v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+		}},
+	}
+	idxBytes, err := json.Marshal(idx)
+	if err != nil {
+		t.Fatalf("json.Marshal(index) = %v", err)
+	}
+
+	var gotListAccept string
+	var fetchedChild string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/repo/manifests/latest", func(w http.ResponseWriter, r *http.Request) {
+		gotListAccept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", string(types.DockerManifestList))
+		w.Write(idxBytes)
+	})
+	mux.HandleFunc("/v2/repo/manifests/"+v6Digest.String(), func(w http.ResponseWriter, r *http.Request) {
+		fetchedChild = v6Digest.String()
+		w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+		w.Write(v6Bytes)
+	})
+	mux.HandleFunc("/v2/repo/manifests/"+v7Digest.String(), func(w http.ResponseWriter, r *http.Request) {
+		fetchedChild = v7Digest.String()
+		w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+		w.Write(v7Bytes)
+	})
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatalf("url.Parse() = %v", err)
+	}
+	ref, err := name.NewTag("localhost:"+u.Port()+"/repo:latest", name.WeakValidation)
+	if err != nil {
+		t.Fatalf("NewTag() = %v", err)
+	}
+
+	img, err := Image(ref, authn.Anonymous, nil, WithPlatform(v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}))
+	if err != nil {
+		t.Fatalf("Image() = %v", err)
+	}
+
+	for _, mt := range acceptableImageMediaTypes {
+		if !strings.Contains(gotListAccept, string(mt)) {
+			t.Errorf("Accept header %q does not contain %q", gotListAccept, mt)
+		}
+	}
+
+	gotManifest, err := img.RawManifest()
+	if err != nil {
+		t.Fatalf("RawManifest() = %v", err)
+	}
+	if !bytes.Equal(gotManifest, v7Bytes) {
+		t.Errorf("RawManifest(); got %s, want %s", gotManifest, v7Bytes)
+	}
+	if fetchedChild != v7Digest.String() {
+		t.Errorf("fetched child manifest %s, want %s (v7)", fetchedChild, v7Digest)
+	}
+}
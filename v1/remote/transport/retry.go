@@ -0,0 +1,251 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// retryableStatusCodes are response codes we consider transient and worth
+// retrying, since registries routinely bounce pulls with these.
+var retryableStatusCodes = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// retryOptions configures retryTransport. See the RetryOption functions for
+// the meaning of each field and its default.
+type retryOptions struct {
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	factor       float64
+	jitter       float64
+	maxAttempts  int
+}
+
+// RetryOption customizes the behavior of NewRetry.
+type RetryOption func(*retryOptions)
+
+// WithInitialDelay sets the delay before the first retry. Default 1s.
+func WithInitialDelay(d time.Duration) RetryOption {
+	return func(o *retryOptions) { o.initialDelay = d }
+}
+
+// WithMaxDelay caps the backoff delay between retries. Default 30s.
+func WithMaxDelay(d time.Duration) RetryOption {
+	return func(o *retryOptions) { o.maxDelay = d }
+}
+
+// WithFactor sets the multiplier applied to the delay after each attempt.
+// Default 2.0.
+func WithFactor(f float64) RetryOption {
+	return func(o *retryOptions) { o.factor = f }
+}
+
+// WithJitter sets the fraction of the computed delay to randomize, to avoid
+// synchronized retries from many clients. Default 0.1.
+func WithJitter(j float64) RetryOption {
+	return func(o *retryOptions) { o.jitter = j }
+}
+
+// WithMaxAttempts sets the maximum number of times a request is attempted,
+// including the first. Default 5.
+func WithMaxAttempts(n int) RetryOption {
+	return func(o *retryOptions) { o.maxAttempts = n }
+}
+
+// retryTransport wraps a RoundTripper, retrying idempotent requests with
+// jittered exponential backoff on transient network errors and status codes.
+type retryTransport struct {
+	inner http.RoundTripper
+	opts  retryOptions
+}
+
+// NewRetry wraps inner in a RoundTripper that retries requests that fail with
+// a transient network error or one of 408, 429, 500, 502, 503, or 504, using
+// jittered exponential backoff. A Retry-After response header, if present,
+// overrides the computed delay.
+//
+// Only requests with a nil body, or a body that req.GetBody can rewind (as
+// http.NewRequest arranges for *bytes.Reader, *bytes.Buffer, and
+// *strings.Reader bodies), are retried; any other request is attempted once
+// and its error or response is returned as-is.
+func NewRetry(inner http.RoundTripper, opts ...RetryOption) http.RoundTripper {
+	o := retryOptions{
+		initialDelay: time.Second,
+		maxDelay:     30 * time.Second,
+		factor:       2.0,
+		jitter:       0.1,
+		maxAttempts:  5,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &retryTransport{inner: inner, opts: o}
+}
+
+func (t *retryTransport) RoundTrip(in *http.Request) (*http.Response, error) {
+	if !isRewindable(in) {
+		return t.inner.RoundTrip(in)
+	}
+
+	var resp *http.Response
+	var err error
+	delay := t.opts.initialDelay
+	for attempt := 0; attempt < t.opts.maxAttempts; attempt++ {
+		if attempt > 0 {
+			req, rerr := rewind(in)
+			if rerr != nil {
+				return nil, rerr
+			}
+			in = req
+			time.Sleep(t.nextDelay(attempt, delay, resp))
+			delay = t.backoff(delay)
+		}
+
+		resp, err = t.inner.RoundTrip(in)
+		if err == nil && !retryableStatusCodes[resp.StatusCode] {
+			return resp, nil
+		}
+		if err != nil && !isRetryable(err) {
+			return resp, err
+		}
+		if attempt < t.opts.maxAttempts-1 && resp != nil {
+			resp.Body.Close()
+		}
+	}
+	return resp, err
+}
+
+// nextDelay returns how long to sleep before the given attempt, honoring a
+// Retry-After header from the previous response when present.
+func (t *retryTransport) nextDelay(attempt int, computed time.Duration, prev *http.Response) time.Duration {
+	if prev != nil {
+		if d, ok := retryAfter(prev); ok {
+			return d
+		}
+	}
+	return jitter(computed, t.opts.jitter)
+}
+
+func (t *retryTransport) backoff(d time.Duration) time.Duration {
+	d = time.Duration(float64(d) * t.opts.factor)
+	if d > t.opts.maxDelay {
+		d = t.opts.maxDelay
+	}
+	return d
+}
+
+func jitter(d time.Duration, frac float64) time.Duration {
+	if frac <= 0 {
+		return d
+	}
+	delta := float64(d) * frac
+	return d + time.Duration(delta*(2*rand.Float64()-1))
+}
+
+// retryAfter parses a Retry-After header, which may be either a number of
+// seconds or an HTTP-date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// isRetryable reports whether err looks like a transient network error worth
+// retrying, rather than e.g. a context cancellation or TLS failure.
+//
+// net.Error's deprecated Temporary() method misses common cases we do want
+// to retry, like a registry closing a keep-alive connection out from under
+// us (ECONNRESET) mid-request, so we unwrap looking for those explicitly and
+// only fall back to Temporary() for anything left over.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	switch e := err.(type) {
+	case *url.Error:
+		return isRetryable(e.Err)
+	case *net.OpError:
+		return isRetryable(e.Err)
+	case *net.DNSError:
+		return e.IsTimeout || e.IsTemporary
+	case syscall.Errno:
+		switch e {
+		case syscall.ECONNRESET, syscall.ECONNREFUSED, syscall.ECONNABORTED, syscall.EPIPE:
+			return true
+		}
+	}
+
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return true
+	}
+	if te, ok := err.(interface{ Temporary() bool }); ok {
+		return te.Temporary()
+	}
+	return false
+}
+
+// isRewindable reports whether req has a body we can safely replay.
+func isRewindable(req *http.Request) bool {
+	if req.Body == nil {
+		return true
+	}
+	return req.GetBody != nil
+}
+
+// rewind returns a copy of req with its body reset to the beginning.
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	out := req.Clone(req.Context())
+	out.Body = body
+	return out, nil
+}
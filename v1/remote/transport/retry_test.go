@@ -0,0 +1,125 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type temporaryError struct{ temporary bool }
+
+func (e *temporaryError) Error() string   { return "temporary error" }
+func (e *temporaryError) Temporary() bool { return e.temporary }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"EOF", io.EOF, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"plain error", errors.New("boom"), false},
+		{"temporary true", &temporaryError{temporary: true}, true},
+		{"temporary false", &temporaryError{temporary: false}, false},
+		{"ECONNRESET", syscall.ECONNRESET, true},
+		{"ECONNREFUSED", syscall.ECONNREFUSED, true},
+		{"EPIPE", syscall.EPIPE, true},
+		{"ENOENT", syscall.ENOENT, false},
+		{"wrapped in url.Error", &url.Error{Op: "Get", URL: "http://x", Err: syscall.ECONNRESET}, true},
+		{"wrapped in net.OpError", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isRetryable(test.err); got != test.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestJitterWithinBounds(t *testing.T) {
+	d := 10 * time.Second
+	frac := 0.2
+	for i := 0; i < 100; i++ {
+		got := jitter(d, frac)
+		min := d - time.Duration(float64(d)*frac)
+		max := d + time.Duration(float64(d)*frac)
+		if got < min || got > max {
+			t.Fatalf("jitter(%v, %v) = %v, want in [%v, %v]", d, frac, got, min, max)
+		}
+	}
+}
+
+func TestJitterZeroFraction(t *testing.T) {
+	d := 5 * time.Second
+	if got := jitter(d, 0); got != d {
+		t.Errorf("jitter(%v, 0) = %v, want %v", d, got, d)
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+	d, ok := retryAfter(resp)
+	if !ok {
+		t.Fatalf("retryAfter() ok = false, want true")
+	}
+	if d != 2*time.Second {
+		t.Errorf("retryAfter() = %v, want 2s", d)
+	}
+}
+
+func TestRetryAfterHTTPDate(t *testing.T) {
+	future := time.Now().Add(10 * time.Second)
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{future.UTC().Format(http.TimeFormat)}}}
+	d, ok := retryAfter(resp)
+	if !ok {
+		t.Fatalf("retryAfter() ok = false, want true")
+	}
+	if d <= 0 || d > 10*time.Second {
+		t.Errorf("retryAfter() = %v, want roughly 10s", d)
+	}
+}
+
+func TestRetryAfterMissing(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	if _, ok := retryAfter(resp); ok {
+		t.Errorf("retryAfter() ok = true, want false")
+	}
+}
+
+func TestIsRewindable(t *testing.T) {
+	withBody := func(body io.ReadCloser, getBody func() (io.ReadCloser, error)) *http.Request {
+		return &http.Request{Body: body, GetBody: getBody}
+	}
+
+	if !isRewindable(withBody(nil, nil)) {
+		t.Errorf("isRewindable(nil body) = false, want true")
+	}
+	if !isRewindable(withBody(http.NoBody, func() (io.ReadCloser, error) { return http.NoBody, nil })) {
+		t.Errorf("isRewindable(body with GetBody) = false, want true")
+	}
+	if isRewindable(withBody(io.NopCloser(nil), nil)) {
+		t.Errorf("isRewindable(opaque body, no GetBody) = true, want false")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package transport implements a registry-authenticated http.RoundTripper.
+package transport
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/authn"
+	"github.com/google/go-containerregistry/name"
+)
+
+// Scope names the access level of a registry request, matching the "scope"
+// parameter of the registry's token endpoint. New accepts it but does not
+// use it.
+type Scope string
+
+const (
+	// PullScope requests read-only access to a repository.
+	PullScope Scope = "pull"
+	// PushScope requests read/write access to a repository.
+	PushScope Scope = "push"
+)
+
+// Option customizes the RoundTripper returned by New.
+type Option func(*options)
+
+type options struct {
+	retryOpts []RetryOption
+	retry     bool
+}
+
+// WithRetry wraps the returned RoundTripper in a retrying one (see NewRetry),
+// so that transient registry errors don't fail the caller's request. It is
+// opt-in: without it, New's RoundTripper is attempted exactly once per call.
+func WithRetry(opts ...RetryOption) Option {
+	return func(o *options) {
+		o.retry = true
+		o.retryOpts = opts
+	}
+}
+
+// New returns a RoundTripper that sets auth's Authorization header on every
+// request, wrapping inner (or http.DefaultTransport if inner is nil). ref and
+// scope are not used: no token exchange with the registry is performed.
+func New(ref name.Reference, auth authn.Authenticator, inner http.RoundTripper, scope Scope, opts ...Option) (http.RoundTripper, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	rt := inner
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	rt = &authTransport{inner: rt, auth: auth}
+
+	if o.retry {
+		rt = NewRetry(rt, o.retryOpts...)
+	}
+	return rt, nil
+}
+
+// authTransport sets the Authorization header on every request using auth.
+type authTransport struct {
+	inner http.RoundTripper
+	auth  authn.Authenticator
+}
+
+func (t *authTransport) RoundTrip(in *http.Request) (*http.Response, error) {
+	hdr, err := t.auth.Authorization()
+	if err != nil {
+		return nil, err
+	}
+
+	out := in.Clone(in.Context())
+	if hdr != "" {
+		out.Header.Set("Authorization", hdr)
+	}
+	return t.inner.RoundTrip(out)
+}
+
+// Scheme returns the URL scheme to use for requests to reg: "http" for
+// registries that are conventionally run without TLS (localhost, .local),
+// and "https" otherwise.
+func Scheme(reg name.Registry) string {
+	host := reg.Name()
+	if host == "localhost" || strings.HasPrefix(host, "localhost:") || strings.HasSuffix(host, ".local") {
+		return "http"
+	}
+	return "https"
+}
@@ -0,0 +1,211 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stream implements a v1.Layer backed by an uncompressed stream
+// whose digest and size aren't known until it has been fully read, so that
+// layers produced by a build pipeline don't need to be buffered to disk
+// before they can be wrapped as a v1.Layer.
+package stream
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/google/go-containerregistry/v1"
+	"github.com/google/go-containerregistry/v1/types"
+)
+
+var (
+	// ErrNotComputed is returned by Digest, DiffID, and Size before the
+	// stream has been fully read via Compressed or Uncompressed.
+	ErrNotComputed = errors.New("stream: digest/diffID/size not computed until stream is consumed")
+
+	// ErrConsumed is returned by Compressed and Uncompressed if they are
+	// called more than once: the layer's content can only be read one time.
+	ErrConsumed = errors.New("stream: tried to read layer content twice")
+)
+
+// Layer is a v1.Layer backed by an io.ReadCloser of uncompressed tar bytes,
+// whose Digest, DiffID, and Size are computed lazily as it's streamed out.
+type Layer struct {
+	blob      io.ReadCloser
+	mediaType types.MediaType
+
+	mu       sync.Mutex
+	consumed bool
+	digest   *v1.Hash
+	diffID   *v1.Hash
+	size     int64
+}
+
+var _ v1.Layer = (*Layer)(nil)
+
+// LayerOption customizes a Layer returned by NewLayer.
+type LayerOption func(*Layer)
+
+// WithMediaType overrides the default media type (types.DockerLayer) that
+// MediaType reports for the layer.
+func WithMediaType(mt types.MediaType) LayerOption {
+	return func(l *Layer) {
+		l.mediaType = mt
+	}
+}
+
+// NewLayer wraps rc, a reader of uncompressed tar bytes, as a v1.Layer. rc is
+// read exactly once, either via Compressed or Uncompressed.
+func NewLayer(rc io.ReadCloser, opts ...LayerOption) *Layer {
+	l := &Layer{
+		blob:      rc,
+		mediaType: types.DockerLayer,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Digest implements v1.Layer. It returns ErrNotComputed until the stream has
+// been consumed.
+func (l *Layer) Digest() (v1.Hash, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.digest == nil {
+		return v1.Hash{}, ErrNotComputed
+	}
+	return *l.digest, nil
+}
+
+// DiffID implements v1.Layer. It returns ErrNotComputed until the stream has
+// been consumed.
+func (l *Layer) DiffID() (v1.Hash, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.diffID == nil {
+		return v1.Hash{}, ErrNotComputed
+	}
+	return *l.diffID, nil
+}
+
+// Size implements v1.Layer. It returns ErrNotComputed until the stream has
+// been consumed.
+func (l *Layer) Size() (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.digest == nil {
+		return 0, ErrNotComputed
+	}
+	return l.size, nil
+}
+
+// MediaType implements v1.Layer.
+func (l *Layer) MediaType() (types.MediaType, error) {
+	return l.mediaType, nil
+}
+
+// Compressed implements v1.Layer. It gzips the underlying stream as it's
+// read, computing the sha256 of both the compressed and uncompressed bytes
+// in a single pass; Digest, DiffID, and Size are populated once the returned
+// ReadCloser has been read to EOF and closed. It may be called only once.
+func (l *Layer) Compressed() (io.ReadCloser, error) {
+	l.mu.Lock()
+	if l.consumed {
+		l.mu.Unlock()
+		return nil, ErrConsumed
+	}
+	l.consumed = true
+	l.mu.Unlock()
+
+	pr, pw := io.Pipe()
+	go l.compress(pw)
+	return pr, nil
+}
+
+// Uncompressed implements v1.Layer by gunzipping the result of Compressed.
+// Like Compressed, it may only be called once across the lifetime of the
+// Layer.
+func (l *Layer) Uncompressed() (io.ReadCloser, error) {
+	rc, err := l.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	// (*gzip.Reader).Close doesn't close the underlying reader, so without
+	// this a caller that Closes early (e.g. on an upload failure) would
+	// leave the pipe, and compress's blocked write to it, alive forever.
+	return &gzipReadCloser{Reader: zr, pipe: rc}, nil
+}
+
+// gzipReadCloser closes both a gzip.Reader and the pipe it's reading from.
+type gzipReadCloser struct {
+	*gzip.Reader
+	pipe io.Closer
+}
+
+func (g *gzipReadCloser) Close() error {
+	if err := g.Reader.Close(); err != nil {
+		g.pipe.Close()
+		return err
+	}
+	return g.pipe.Close()
+}
+
+// countWriter counts the bytes written through it.
+type countWriter struct {
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// compress drains l.blob into pw as gzip, hashing the uncompressed bytes
+// (for DiffID) and the gzipped bytes (for Digest and Size) as it goes.
+func (l *Layer) compress(pw *io.PipeWriter) {
+	defer l.blob.Close()
+
+	uncompressedHasher := sha256.New()
+	compressedHasher := sha256.New()
+	counter := &countWriter{}
+	zw := gzip.NewWriter(io.MultiWriter(pw, compressedHasher, counter))
+
+	if _, err := io.Copy(zw, io.TeeReader(l.blob, uncompressedHasher)); err != nil {
+		zw.Close()
+		pw.CloseWithError(err)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		pw.CloseWithError(err)
+		return
+	}
+
+	digest := v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(compressedHasher.Sum(nil))}
+	diffID := v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(uncompressedHasher.Sum(nil))}
+
+	l.mu.Lock()
+	l.digest = &digest
+	l.diffID = &diffID
+	l.size = counter.n
+	l.mu.Unlock()
+
+	pw.Close()
+}
@@ -0,0 +1,168 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-containerregistry/v1"
+)
+
+func TestDigestDiffIDSizeBeforeConsumption(t *testing.T) {
+	l := NewLayer(ioutil.NopCloser(bytes.NewReader([]byte("hello"))))
+
+	if _, err := l.Digest(); err != ErrNotComputed {
+		t.Errorf("Digest() before consumption = %v, want ErrNotComputed", err)
+	}
+	if _, err := l.DiffID(); err != ErrNotComputed {
+		t.Errorf("DiffID() before consumption = %v, want ErrNotComputed", err)
+	}
+	if _, err := l.Size(); err != ErrNotComputed {
+		t.Errorf("Size() before consumption = %v, want ErrNotComputed", err)
+	}
+}
+
+func TestCompressedThenUncompressedFails(t *testing.T) {
+	l := NewLayer(ioutil.NopCloser(bytes.NewReader([]byte("hello"))))
+
+	rc, err := l.Compressed()
+	if err != nil {
+		t.Fatalf("Compressed() = %v", err)
+	}
+	rc.Close()
+
+	if _, err := l.Uncompressed(); err != ErrConsumed {
+		t.Errorf("Uncompressed() after Compressed() = %v, want ErrConsumed", err)
+	}
+}
+
+func TestCompressedTwiceFails(t *testing.T) {
+	l := NewLayer(ioutil.NopCloser(bytes.NewReader([]byte("hello"))))
+
+	rc, err := l.Compressed()
+	if err != nil {
+		t.Fatalf("Compressed() = %v", err)
+	}
+	rc.Close()
+
+	if _, err := l.Compressed(); err != ErrConsumed {
+		t.Errorf("Compressed() twice = %v, want ErrConsumed", err)
+	}
+}
+
+func TestDigestAndDiffIDAfterConsumption(t *testing.T) {
+	want := []byte("hello layer contents")
+	l := NewLayer(ioutil.NopCloser(bytes.NewReader(want)))
+
+	rc, err := l.Compressed()
+	if err != nil {
+		t.Fatalf("Compressed() = %v", err)
+	}
+	gotCompressed, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll(Compressed()) = %v", err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	wantDigestHasher := sha256.Sum256(gotCompressed)
+	wantDigest := v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(wantDigestHasher[:])}
+
+	wantDiffIDHasher := sha256.Sum256(want)
+	wantDiffID := v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(wantDiffIDHasher[:])}
+
+	digest, err := l.Digest()
+	if err != nil {
+		t.Fatalf("Digest() = %v", err)
+	}
+	if digest != wantDigest {
+		t.Errorf("Digest() = %v, want %v", digest, wantDigest)
+	}
+
+	diffID, err := l.DiffID()
+	if err != nil {
+		t.Fatalf("DiffID() = %v", err)
+	}
+	if diffID != wantDiffID {
+		t.Errorf("DiffID() = %v, want %v", diffID, wantDiffID)
+	}
+
+	size, err := l.Size()
+	if err != nil {
+		t.Fatalf("Size() = %v", err)
+	}
+	if size != int64(len(gotCompressed)) {
+		t.Errorf("Size() = %d, want %d", size, len(gotCompressed))
+	}
+
+	// Sanity check that what we compressed actually gunzips back to the
+	// original content.
+	zr, err := gzip.NewReader(bytes.NewReader(gotCompressed))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() = %v", err)
+	}
+	gotUncompressed, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("ReadAll(gzip) = %v", err)
+	}
+	if !bytes.Equal(gotUncompressed, want) {
+		t.Errorf("uncompressed contents; got %q, want %q", gotUncompressed, want)
+	}
+}
+
+// closeTrackingPipe wraps an io.ReadCloser to record whether Close was
+// called, so tests can confirm Uncompressed's gzipReadCloser propagates
+// Close down to the pipe reader it wraps.
+type closeTrackingPipe struct {
+	io.Reader
+	closed bool
+}
+
+func (p *closeTrackingPipe) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestUncompressedCloseClosesUnderlyingPipe(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte("hello")); err != nil {
+		t.Fatalf("gzip Write() = %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip Close() = %v", err)
+	}
+
+	pipe := &closeTrackingPipe{Reader: bytes.NewReader(buf.Bytes())}
+	zr, err := gzip.NewReader(pipe)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() = %v", err)
+	}
+	grc := &gzipReadCloser{Reader: zr, pipe: pipe}
+
+	if err := grc.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	if !pipe.closed {
+		t.Errorf("underlying pipe was not closed")
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tarball provides methods for reading and writing v1.Images as
+// tarballs in the `docker save` format.
+package tarball
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/google/go-containerregistry/name"
+	"github.com/google/go-containerregistry/v1"
+	"github.com/google/go-containerregistry/v1/partial"
+	"github.com/google/go-containerregistry/v1/types"
+)
+
+// Opener is a thunk for opening a tarball, so that an image may be read more
+// than once.
+type Opener func() (io.ReadCloser, error)
+
+// manifestEntry mirrors a single entry of the `docker save` manifest.json.
+type manifestEntry struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+// configFile is the subset of v1.ConfigFile we need to map diff IDs to their
+// layer within the tarball.
+type configFile struct {
+	RootFS struct {
+		DiffIDs []v1.Hash `json:"diff_ids"`
+	} `json:"rootfs"`
+}
+
+type tarImage struct {
+	opener Opener
+	tag    *name.Tag
+
+	loadOnce sync.Once
+	loadErr  error
+	manifest manifestEntry
+	config   []byte
+}
+
+var _ partial.UncompressedImageCore = (*tarImage)(nil)
+
+// Image returns a v1.Image read from the tarball returned by opener, which
+// must be in the `docker save` format. If the tarball contains more than one
+// image, tag selects which one to read; it may be nil if there is only one.
+func Image(opener Opener, tag *name.Tag) (v1.Image, error) {
+	img := &tarImage{
+		opener: opener,
+		tag:    tag,
+	}
+	if err := img.load(); err != nil {
+		return nil, err
+	}
+	return partial.UncompressedToImage(img)
+}
+
+func (r *tarImage) load() error {
+	r.loadOnce.Do(func() {
+		entry, err := r.findManifestEntry()
+		if err != nil {
+			r.loadErr = err
+			return
+		}
+		r.manifest = *entry
+
+		config, err := r.readTarFile(r.manifest.Config)
+		if err != nil {
+			r.loadErr = err
+			return
+		}
+		r.config = config
+	})
+	return r.loadErr
+}
+
+// readTarFile opens the tarball and returns the contents of the entry
+// matching name.
+func (r *tarImage) readTarFile(name string) ([]byte, error) {
+	rc, err := r.opener()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name == name {
+			return ioutil.ReadAll(tr)
+		}
+	}
+	return nil, fmt.Errorf("tarball: %q not found", name)
+}
+
+func (r *tarImage) findManifestEntry() (*manifestEntry, error) {
+	b, err := r.readTarFile("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	var manifests []manifestEntry
+	if err := json.Unmarshal(b, &manifests); err != nil {
+		return nil, err
+	}
+
+	if r.tag == nil {
+		if len(manifests) != 1 {
+			return nil, fmt.Errorf("tarball: contains %d images, expected 1; specify a tag", len(manifests))
+		}
+		return &manifests[0], nil
+	}
+
+	want := r.tag.String()
+	for _, m := range manifests {
+		for _, rt := range m.RepoTags {
+			if rt == want {
+				return &m, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("tarball: does not contain image tagged %s", want)
+}
+
+func (r *tarImage) RawConfigFile() ([]byte, error) {
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r.config, nil
+}
+
+func (r *tarImage) MediaType() (types.MediaType, error) {
+	return types.DockerManifestSchema2, nil
+}
+
+func (r *tarImage) diffIDToLayerPath(diffID v1.Hash) (string, error) {
+	if err := r.load(); err != nil {
+		return "", err
+	}
+	var cfg configFile
+	if err := json.Unmarshal(r.config, &cfg); err != nil {
+		return "", err
+	}
+	for i, d := range cfg.RootFS.DiffIDs {
+		if d == diffID {
+			if i >= len(r.manifest.Layers) {
+				return "", fmt.Errorf("tarball: manifest.json has fewer layers than config's diff_ids")
+			}
+			return r.manifest.Layers[i], nil
+		}
+	}
+	return "", fmt.Errorf("tarball: no layer with diffID %s", diffID)
+}
+
+// UncompressedLayer implements partial.UncompressedImageCore.
+func (r *tarImage) UncompressedLayer(diffID v1.Hash) (partial.UncompressedLayer, error) {
+	path, err := r.diffIDToLayerPath(diffID)
+	if err != nil {
+		return nil, err
+	}
+	return &uncompressedLayer{
+		diffID: diffID,
+		opener: r.opener,
+		path:   path,
+	}, nil
+}
+
+// uncompressedLayer implements partial.UncompressedLayer for a single entry
+// within the tarball.
+type uncompressedLayer struct {
+	diffID v1.Hash
+	opener Opener
+	path   string
+}
+
+func (ul *uncompressedLayer) DiffID() (v1.Hash, error) {
+	return ul.diffID, nil
+}
+
+func (ul *uncompressedLayer) Uncompressed() (io.ReadCloser, error) {
+	rc, err := ul.opener()
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			rc.Close()
+			return nil, fmt.Errorf("tarball: layer %q not found", ul.path)
+		}
+		if err != nil {
+			rc.Close()
+			return nil, err
+		}
+		if hdr.Name == ul.path {
+			return &tarEntryReadCloser{Reader: tr, closer: rc}, nil
+		}
+	}
+}
+
+// tarEntryReadCloser closes the underlying tarball once the caller is done
+// reading a single entry out of it.
+type tarEntryReadCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (t *tarEntryReadCloser) Close() error {
+	return t.closer.Close()
+}
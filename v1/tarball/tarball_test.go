@@ -0,0 +1,165 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tarball
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-containerregistry/name"
+	"github.com/google/go-containerregistry/v1"
+	"github.com/google/go-containerregistry/v1/types"
+)
+
+// fakeLayer is a v1.Layer over a fixed, already-uncompressed blob of tar
+// bytes, so tests don't need a real gzip round trip to exercise Write/Image.
+type fakeLayer struct {
+	uncompressed []byte
+	diffID       v1.Hash
+}
+
+func newFakeLayer(uncompressed []byte) (*fakeLayer, error) {
+	diffID, _, err := v1.SHA256(ioutil.NopCloser(bytes.NewReader(uncompressed)))
+	if err != nil {
+		return nil, err
+	}
+	return &fakeLayer{uncompressed: uncompressed, diffID: diffID}, nil
+}
+
+func (l *fakeLayer) Digest() (v1.Hash, error)            { return l.diffID, nil }
+func (l *fakeLayer) DiffID() (v1.Hash, error)            { return l.diffID, nil }
+func (l *fakeLayer) Size() (int64, error)                { return int64(len(l.uncompressed)), nil }
+func (l *fakeLayer) MediaType() (types.MediaType, error) { return types.DockerLayer, nil }
+func (l *fakeLayer) Compressed() (io.ReadCloser, error)  { return l.Uncompressed() }
+func (l *fakeLayer) Uncompressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.uncompressed)), nil
+}
+
+// fakeImage is a minimal v1.Image backed by a single fakeLayer, enough to
+// drive Write without needing the rest of the real image machinery.
+type fakeImage struct {
+	config []byte
+	layers []v1.Layer
+}
+
+func (i *fakeImage) Layers() ([]v1.Layer, error)             { return i.layers, nil }
+func (i *fakeImage) MediaType() (types.MediaType, error)     { return types.DockerManifestSchema2, nil }
+func (i *fakeImage) Size() (int64, error)                    { return 0, nil }
+func (i *fakeImage) ConfigName() (v1.Hash, error)            { return v1.Hash{}, nil }
+func (i *fakeImage) ConfigFile() (*v1.ConfigFile, error)     { return nil, nil }
+func (i *fakeImage) RawConfigFile() ([]byte, error)          { return i.config, nil }
+func (i *fakeImage) Digest() (v1.Hash, error)                { return v1.Hash{}, nil }
+func (i *fakeImage) Manifest() (*v1.Manifest, error)         { return nil, nil }
+func (i *fakeImage) RawManifest() ([]byte, error)            { return nil, nil }
+func (i *fakeImage) LayerByDigest(v1.Hash) (v1.Layer, error) { return nil, nil }
+func (i *fakeImage) LayerByDiffID(v1.Hash) (v1.Layer, error) { return nil, nil }
+
+var _ v1.Image = (*fakeImage)(nil)
+var _ v1.Layer = (*fakeLayer)(nil)
+
+// TestWriteImageRoundTrip writes an image to a tarball and reads it back,
+// checking that the config and layer contents survive unchanged.
+func TestWriteImageRoundTrip(t *testing.T) {
+	layer, err := newFakeLayer([]byte("hello layer\n"))
+	if err != nil {
+		t.Fatalf("newFakeLayer() = %v", err)
+	}
+	config := []byte(fmt.Sprintf(`{"rootfs":{"diff_ids":["%s"]}}`, layer.diffID))
+	img := &fakeImage{config: config, layers: []v1.Layer{layer}}
+
+	tag, err := name.NewTag("test.io/repo:latest", name.WeakValidation)
+	if err != nil {
+		t.Fatalf("NewTag() = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(tag, img, &buf); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	b := buf.Bytes()
+	got, err := Image(func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}, &tag)
+	if err != nil {
+		t.Fatalf("Image() = %v", err)
+	}
+
+	gotConfig, err := got.RawConfigFile()
+	if err != nil {
+		t.Fatalf("RawConfigFile() = %v", err)
+	}
+	if !bytes.Equal(gotConfig, config) {
+		t.Errorf("RawConfigFile(); got %s, want %s", gotConfig, config)
+	}
+
+	gotLayers, err := got.Layers()
+	if err != nil {
+		t.Fatalf("Layers() = %v", err)
+	}
+	if len(gotLayers) != 1 {
+		t.Fatalf("len(Layers()) = %d, want 1", len(gotLayers))
+	}
+
+	rc, err := gotLayers[0].Uncompressed()
+	if err != nil {
+		t.Fatalf("Uncompressed() = %v", err)
+	}
+	defer rc.Close()
+	gotBytes, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll() = %v", err)
+	}
+	if !bytes.Equal(gotBytes, layer.uncompressed) {
+		t.Errorf("layer contents; got %q, want %q", gotBytes, layer.uncompressed)
+	}
+}
+
+// TestImageMissingTag asserts that reading back a tarball with a tag that
+// doesn't appear in its manifest.json fails, rather than silently picking
+// an arbitrary image.
+func TestImageMissingTag(t *testing.T) {
+	layer, err := newFakeLayer([]byte("hello layer\n"))
+	if err != nil {
+		t.Fatalf("newFakeLayer() = %v", err)
+	}
+	config := []byte(fmt.Sprintf(`{"rootfs":{"diff_ids":["%s"]}}`, layer.diffID))
+	img := &fakeImage{config: config, layers: []v1.Layer{layer}}
+
+	tag, err := name.NewTag("test.io/repo:latest", name.WeakValidation)
+	if err != nil {
+		t.Fatalf("NewTag() = %v", err)
+	}
+	other, err := name.NewTag("test.io/repo:other", name.WeakValidation)
+	if err != nil {
+		t.Fatalf("NewTag() = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(tag, img, &buf); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	b := buf.Bytes()
+	got, err := Image(func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}, &other)
+	if err == nil {
+		t.Errorf("Image() = %v, want error", got)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 Google LLC All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tarball
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/google/go-containerregistry/name"
+	"github.com/google/go-containerregistry/v1"
+)
+
+// Write serializes img to w in the `docker save` format, tagged with ref if
+// ref is a name.Tag.
+func Write(ref name.Reference, img v1.Image, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+
+	layers, err := img.Layers()
+	if err != nil {
+		return err
+	}
+
+	layerPaths := make([]string, len(layers))
+	for i, l := range layers {
+		diffID, err := l.DiffID()
+		if err != nil {
+			return err
+		}
+		layerPaths[i] = fmt.Sprintf("%s.tar", diffID.Hex)
+
+		r, err := l.Uncompressed()
+		if err != nil {
+			return err
+		}
+		err = writeTarEntry(tw, layerPaths[i], r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	cfgBlob, err := img.RawConfigFile()
+	if err != nil {
+		return err
+	}
+	cfgDigest, _, err := v1.SHA256(ioutil.NopCloser(bytes.NewReader(cfgBlob)))
+	if err != nil {
+		return err
+	}
+	cfgName := cfgDigest.Hex + ".json"
+	if err := writeTarEntry(tw, cfgName, bytes.NewReader(cfgBlob)); err != nil {
+		return err
+	}
+
+	var repoTags []string
+	if tag, ok := ref.(name.Tag); ok {
+		repoTags = []string{tag.String()}
+	}
+
+	m := []manifestEntry{{
+		Config:   cfgName,
+		RepoTags: repoTags,
+		Layers:   layerPaths,
+	}}
+	mb, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return writeTarEntry(tw, "manifest.json", bytes.NewReader(mb))
+}
+
+// writeTarEntry writes name as a tar entry containing the contents of r.
+func writeTarEntry(tw *tar.Writer, name string, r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name: name,
+		Mode: 0644,
+		Size: int64(len(b)),
+	}); err != nil {
+		return err
+	}
+	_, err = tw.Write(b)
+	return err
+}